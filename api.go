@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// serveHTTP runs the opt-in local control API and Prometheus endpoint. It
+// is only started when config.toml sets [http] enabled = true.
+func (s *supervisor) serveHTTP(listen, configPath string) {
+	listener, err := httpListener(listen)
+	if err != nil {
+		return
+	}
+	defer listener.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", s.handleHTTPStatus)
+	mux.HandleFunc("/start", s.handleHTTPStart)
+	mux.HandleFunc("/stop", s.handleHTTPStop)
+	mux.HandleFunc("/profiles", httpProfilesHandler(configPath))
+	mux.HandleFunc("/log", httpLogHandler())
+	mux.HandleFunc("/metrics", s.handleHTTPMetrics)
+
+	http.Serve(listener, mux)
+}
+
+// httpListener binds listen either as a "host:port" TCP address or, when
+// prefixed with "unix:", as a Unix socket.
+func httpListener(listen string) (net.Listener, error) {
+	if strings.HasPrefix(listen, "unix:") {
+		path := strings.TrimPrefix(listen, "unix:")
+		os.Remove(path)
+		return net.Listen("unix", path)
+	}
+	return net.Listen("tcp", listen)
+}
+
+func (s *supervisor) handleHTTPStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.statusSnapshot())
+}
+
+// handleHTTPStart forces a reconnect of the profile this supervisor is
+// already running; it does not switch profiles, since one supervisor owns
+// exactly one tunnel.
+func (s *supervisor) handleHTTPStart(w http.ResponseWriter, r *http.Request) {
+	s.killCmd()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"result": "restarting"})
+}
+
+func (s *supervisor) handleHTTPStop(w http.ResponseWriter, r *http.Request) {
+	s.setStopping(true)
+	s.killCmd()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"result": "stopping"})
+}
+
+func httpProfilesHandler(configPath string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cfg, err := loadZvpnConfig(configPath)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"default_profile": cfg.DefaultProfile,
+			"profiles":        cfg.Profiles,
+		})
+	}
+}
+
+func httpLogHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tail := 100
+		if n, err := strconv.Atoi(r.URL.Query().Get("tail")); err == nil && n > 0 {
+			tail = n
+		}
+
+		data, err := os.ReadFile(logFile)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+		if len(lines) > tail {
+			lines = lines[len(lines)-tail:]
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string][]string{"lines": lines})
+	}
+}
+
+func (s *supervisor) handleHTTPMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write([]byte(s.renderPrometheus()))
+}
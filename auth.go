@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// credsDir holds one encrypted credential file per config, named after the
+// config's basename, e.g. ~/.zvpn/creds/myprovider.ovpn.gpg
+const credsDir = "creds"
+
+type credentials struct {
+	Username string
+	Password string
+	OTP      string
+}
+
+// needsAuthUserPass reports whether config declares "auth-user-pass" with
+// no inline filename, meaning openvpn will query for credentials over the
+// management interface instead of reading them from disk itself.
+func needsAuthUserPass(config string) (bool, error) {
+	data, err := os.ReadFile(config)
+	if err != nil {
+		return false, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 1 && fields[0] == "auth-user-pass" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func credsPath(config string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, configDir, credsDir, filepath.Base(config)+".gpg"), nil
+}
+
+// resolveCredsPath picks the encrypted credentials file for a profile: its
+// creds_file override (resolved relative to configPath, like Config) if one
+// is set, otherwise the default path derived from the config's basename.
+func resolveCredsPath(configPath, config, credsFile string) (string, error) {
+	if credsFile == "" {
+		return credsPath(config)
+	}
+	if filepath.IsAbs(credsFile) {
+		return credsFile, nil
+	}
+	return filepath.Join(configPath, credsFile), nil
+}
+
+// ensureCredentialsCached prompts for and stores credentials for config if
+// it requires auth-user-pass and none are cached yet. It must run before
+// startService detaches, since the supervisor has no controlling terminal.
+func ensureCredentialsCached(configPath, config, credsFile string) error {
+	needed, err := needsAuthUserPass(config)
+	if err != nil || !needed {
+		return err
+	}
+
+	path, err := resolveCredsPath(configPath, config, credsFile)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	fmt.Printf("%s requires a username and password.\n", filepath.Base(config))
+	creds, err := promptCredentials()
+	if err != nil {
+		return err
+	}
+	return saveCredentials(path, creds)
+}
+
+// loginCommand implements `zvpn --login <config>`, pre-caching credentials
+// for a config ahead of time.
+func loginCommand(configPath, name string) {
+	if !strings.HasSuffix(name, ".ovpn") {
+		name += ".ovpn"
+	}
+	config := filepath.Join(configPath, name)
+	if _, err := os.Stat(config); err != nil {
+		fmt.Println("No such configuration file:", config)
+		return
+	}
+
+	path, err := credsPath(config)
+	if err != nil {
+		fmt.Println("Failed to resolve credential storage path:", err)
+		return
+	}
+
+	fmt.Printf("Caching credentials for %s\n", name)
+	creds, err := promptCredentials()
+	if err != nil {
+		fmt.Println("Failed to read credentials:", err)
+		return
+	}
+	if err := saveCredentials(path, creds); err != nil {
+		fmt.Println("Failed to save credentials:", err)
+		return
+	}
+	fmt.Println("Credentials cached:", path)
+}
+
+func promptCredentials() (credentials, error) {
+	reader := bufio.NewReader(os.Stdin)
+
+	fmt.Print("Username: ")
+	username, err := reader.ReadString('\n')
+	if err != nil {
+		return credentials{}, err
+	}
+
+	fmt.Print("Password: ")
+	password, err := reader.ReadString('\n')
+	if err != nil {
+		return credentials{}, err
+	}
+
+	fmt.Print("OTP / static challenge (leave blank if none): ")
+	otp, _ := reader.ReadString('\n')
+
+	return credentials{
+		Username: strings.TrimSpace(username),
+		Password: strings.TrimSpace(password),
+		OTP:      strings.TrimSpace(otp),
+	}, nil
+}
+
+// saveCredentials shells out to gpg for symmetric encryption, the same way
+// the rest of zvpn shells out to openvpn and pkill rather than linking a
+// crypto library.
+func saveCredentials(path string, creds credentials) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	plain := fmt.Sprintf("%s\n%s\n%s\n", creds.Username, creds.Password, creds.OTP)
+	tmp, err := os.CreateTemp("", "zvpn-creds-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(plain); err != nil {
+		tmp.Close()
+		return err
+	}
+	tmp.Close()
+
+	os.Remove(path)
+	cmd := exec.Command("gpg", "--symmetric", "--cipher-algo", "AES256", "--output", path, tmp.Name())
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func loadCredentials(path string) (credentials, error) {
+	cmd := exec.Command("gpg", "--quiet", "--batch", "--decrypt", path)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return credentials{}, err
+	}
+
+	lines := strings.Split(string(out), "\n")
+	for len(lines) < 3 {
+		lines = append(lines, "")
+	}
+	return credentials{
+		Username: strings.TrimSpace(lines[0]),
+		Password: strings.TrimSpace(lines[1]),
+		OTP:      strings.TrimSpace(lines[2]),
+	}, nil
+}
+
+// handlePasswordRequest answers a ">PASSWORD:Need '...'" challenge from the
+// management interface using cached credentials, if any are available. It
+// runs inside the detached supervisor, so it cannot prompt interactively;
+// if nothing is cached it just logs and leaves the request unanswered.
+func handlePasswordRequest(mgmt *managementClient, configPath, config, credsFile, raw string) {
+	path, err := resolveCredsPath(configPath, config, credsFile)
+	if err != nil {
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		fmt.Println("Credentials required but none cached; run 'zvpn --login' first")
+		return
+	}
+
+	creds, err := loadCredentials(path)
+	if err != nil {
+		fmt.Println("Failed to decrypt cached credentials:", err)
+		return
+	}
+
+	switch {
+	case strings.Contains(raw, "Need 'Auth-Token'"):
+		mgmt.send(fmt.Sprintf("password \"Auth-Token\" %s", mgmtQuote(creds.OTP)))
+	case strings.Contains(raw, "Need 'Auth'"):
+		mgmt.send(fmt.Sprintf("username \"Auth\" %s", mgmtQuote(creds.Username)))
+		mgmt.send(fmt.Sprintf("password \"Auth\" %s", mgmtQuote(creds.Password)))
+	}
+}
+
+// mgmtQuote escapes a value per the management interface's quoting rules
+// (management-notes.txt): backslashes and double quotes are backslash-
+// escaped and the result is wrapped in double quotes, so a credential
+// containing a space or quote can't be split or corrupt the command.
+func mgmtQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
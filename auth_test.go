@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNeedsAuthUserPass(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   bool
+	}{
+		{
+			name:   "bare directive needs credentials",
+			config: "client\ndev tun\nauth-user-pass\nremote vpn.example.com 1194\n",
+			want:   true,
+		},
+		{
+			name:   "directive with inline file does not",
+			config: "client\nauth-user-pass creds.txt\n",
+			want:   false,
+		},
+		{
+			name:   "no directive at all",
+			config: "client\ndev tun\nremote vpn.example.com 1194\n",
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.ovpn")
+			if err := os.WriteFile(path, []byte(tt.config), 0600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := needsAuthUserPass(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("needsAuthUserPass() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMgmtQuote(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "plain value", in: "hunter2", want: `"hunter2"`},
+		{name: "embedded space", in: "pass word", want: `"pass word"`},
+		{name: "embedded quote", in: `pa"ss`, want: `"pa\"ss"`},
+		{name: "embedded backslash", in: `pa\ss`, want: `"pa\\ss"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mgmtQuote(tt.in); got != tt.want {
+				t.Errorf("mgmtQuote(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// activeBackendFile records which backend is currently running and enough
+// state for --stop/--status to address it, since OpenVPN's long-running
+// supervisor and WireGuard's synchronous wg-quick calls are driven very
+// differently under the hood.
+const activeBackendFile = "/tmp/zvpn.backend"
+
+const (
+	backendOpenVPN   = "openvpn"
+	backendWireGuard = "wireguard"
+)
+
+// Backend is the common surface zvpn drives a VPN connection through,
+// implemented once for OpenVPN (via the management-interface supervisor)
+// and once for WireGuard (via wg-quick / wgctrl).
+type Backend interface {
+	Start() error
+	Stop() error
+	Status() (string, error)
+	Reload() error
+}
+
+// detectBackend infers which backend owns a config file from its
+// extension: .ovpn files are OpenVPN, .conf files with a [Interface]
+// section are WireGuard.
+func detectBackend(name string) string {
+	if strings.HasSuffix(name, ".conf") {
+		return backendWireGuard
+	}
+	return backendOpenVPN
+}
+
+func writeActiveBackend(kind, detail string) error {
+	return os.WriteFile(activeBackendFile, []byte(kind+"|"+detail), 0644)
+}
+
+func readActiveBackend() (kind, detail string, err error) {
+	data, err := os.ReadFile(activeBackendFile)
+	if err != nil {
+		return "", "", err
+	}
+	fields := strings.SplitN(string(data), "|", 2)
+	if len(fields) != 2 {
+		return fields[0], "", nil
+	}
+	return fields[0], fields[1], nil
+}
+
+func clearActiveBackend() {
+	os.Remove(activeBackendFile)
+}
+
+// listBackendConfigs scans configPath for both .ovpn and .conf files,
+// returning each name alongside the backend that would drive it.
+func listBackendConfigs(configPath string) ([]string, []string, error) {
+	files, err := os.ReadDir(configPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var names, backends []string
+	for _, file := range files {
+		name := file.Name()
+		if strings.HasSuffix(name, ".ovpn") {
+			names = append(names, name)
+			backends = append(backends, backendOpenVPN)
+		} else if strings.HasSuffix(name, ".conf") {
+			names = append(names, name)
+			backends = append(backends, backendWireGuard)
+		}
+	}
+	return names, backends, nil
+}
+
+// backendForProfile resolves a Profile to the Backend that should drive it
+// and the absolute path to its config file.
+func backendForProfile(configPath, profileName string, profile Profile) (Backend, string) {
+	config := profile.Config
+	if !filepath.IsAbs(config) {
+		config = filepath.Join(configPath, config)
+	}
+
+	kind := profile.Backend
+	if kind == "" {
+		kind = detectBackend(config)
+	}
+
+	if kind == backendWireGuard {
+		return &wireguardBackend{confPath: config, upScript: profile.UpScript, downScript: profile.DownScript}, config
+	}
+	return &openvpnBackend{configPath: configPath, profileName: profileName, config: config, credsFile: profile.CredsFile}, config
+}
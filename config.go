@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// configFileName is the profile-based config that replaces the old
+// single-line .last_config file.
+const configFileName = "config.toml"
+
+// logFile and controlSocket default to the old hard-coded paths but can be
+// overridden per-install via config.toml's [paths] table.
+var logFile = "/tmp/zvpn.log"
+var controlSocket = "/tmp/zvpn.sock"
+
+// Profile is one named entry under [profiles.<name>] in config.toml.
+type Profile struct {
+	Config         string   `toml:"config"`
+	Backend        string   `toml:"backend"`
+	ExtraArgs      []string `toml:"extra_args"`
+	CredsFile      string   `toml:"creds_file"`
+	Killswitch     bool     `toml:"killswitch"`
+	UpScript       string   `toml:"up_script"`
+	DownScript     string   `toml:"down_script"`
+	ExpectedRemote string   `toml:"expected_remote"`
+}
+
+// zvpnConfig is the parsed form of ~/.zvpn/config.toml.
+type zvpnConfig struct {
+	DefaultProfile string             `toml:"default_profile"`
+	Paths          pathsConfig        `toml:"paths"`
+	HTTP           httpConfig         `toml:"http"`
+	Profiles       map[string]Profile `toml:"profiles"`
+}
+
+type pathsConfig struct {
+	LogPath    string `toml:"log_path"`
+	SocketPath string `toml:"socket_path"`
+}
+
+// httpConfig is the opt-in local control API and metrics endpoint.
+type httpConfig struct {
+	Enabled bool   `toml:"enabled"`
+	Listen  string `toml:"listen"`
+}
+
+func configFilePath(configPath string) string {
+	return filepath.Join(configPath, configFileName)
+}
+
+// loadZvpnConfig reads config.toml, applying its [paths] overrides to the
+// package-level logFile/controlSocket. A missing file is not an error: it
+// just means no profiles have been defined yet.
+func loadZvpnConfig(configPath string) (*zvpnConfig, error) {
+	cfg := &zvpnConfig{Profiles: map[string]Profile{}}
+
+	path := configFilePath(configPath)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return cfg, nil
+	}
+
+	if _, err := toml.DecodeFile(path, cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = map[string]Profile{}
+	}
+	if cfg.Paths.LogPath != "" {
+		logFile = cfg.Paths.LogPath
+	}
+	if cfg.Paths.SocketPath != "" {
+		controlSocket = cfg.Paths.SocketPath
+	}
+	return cfg, nil
+}
+
+func (cfg *zvpnConfig) save(configPath string) error {
+	f, err := os.Create(configFilePath(configPath))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return toml.NewEncoder(f).Encode(cfg)
+}
+
+// resolveProfile returns the profile to use for `zvpn --start [name]`: the
+// named profile if one was given, otherwise default_profile.
+func (cfg *zvpnConfig) resolveProfile(name string) (Profile, string, error) {
+	if name == "" {
+		name = cfg.DefaultProfile
+	}
+	if name == "" {
+		return Profile{}, "", fmt.Errorf("no profile given and no default_profile set")
+	}
+	profile, ok := cfg.Profiles[name]
+	if !ok {
+		return Profile{}, "", fmt.Errorf("unknown profile %q", name)
+	}
+	return profile, name, nil
+}
+
+// profilesCommand implements `zvpn profiles list|add|remove|edit`.
+func profilesCommand(configPath string, args []string) {
+	cfg, err := loadZvpnConfig(configPath)
+	if err != nil {
+		fmt.Println("Failed to read config.toml:", err)
+		return
+	}
+
+	if len(args) == 0 {
+		fmt.Println("Usage: zvpn profiles list|add|remove|edit")
+		return
+	}
+
+	switch args[0] {
+	case "list":
+		profilesList(cfg)
+	case "add":
+		if len(args) < 3 {
+			fmt.Println("Usage: zvpn profiles add <name> <config.ovpn>")
+			return
+		}
+		profilesAdd(configPath, cfg, args[1], args[2])
+	case "remove":
+		if len(args) < 2 {
+			fmt.Println("Usage: zvpn profiles remove <name>")
+			return
+		}
+		profilesRemove(configPath, cfg, args[1])
+	case "edit":
+		if len(args) < 4 {
+			fmt.Println("Usage: zvpn profiles edit <name> <field> <value>")
+			return
+		}
+		profilesEdit(configPath, cfg, args[1], args[2], args[3])
+	default:
+		fmt.Println("Usage: zvpn profiles list|add|remove|edit")
+	}
+}
+
+func profilesList(cfg *zvpnConfig) {
+	if len(cfg.Profiles) == 0 {
+		fmt.Println("No profiles configured. Use 'zvpn profiles add <name> <config.ovpn>'.")
+		return
+	}
+	for name, profile := range cfg.Profiles {
+		marker := ""
+		if name == cfg.DefaultProfile {
+			marker = " (default)"
+		}
+		fmt.Printf("%s%s -> %s\n", name, marker, profile.Config)
+	}
+}
+
+func profilesAdd(configPath string, cfg *zvpnConfig, name, config string) {
+	cfg.Profiles[name] = Profile{Config: config}
+	if cfg.DefaultProfile == "" {
+		cfg.DefaultProfile = name
+	}
+	if err := cfg.save(configPath); err != nil {
+		fmt.Println("Failed to save config.toml:", err)
+		return
+	}
+	fmt.Printf("Added profile %q -> %s\n", name, config)
+}
+
+func profilesRemove(configPath string, cfg *zvpnConfig, name string) {
+	if _, ok := cfg.Profiles[name]; !ok {
+		fmt.Println("No such profile:", name)
+		return
+	}
+	delete(cfg.Profiles, name)
+	if cfg.DefaultProfile == name {
+		cfg.DefaultProfile = ""
+	}
+	if err := cfg.save(configPath); err != nil {
+		fmt.Println("Failed to save config.toml:", err)
+		return
+	}
+	fmt.Println("Removed profile:", name)
+}
+
+// profilesEdit sets one field of a profile, e.g.
+// `zvpn profiles edit work killswitch true`.
+func profilesEdit(configPath string, cfg *zvpnConfig, name, field, value string) {
+	profile, ok := cfg.Profiles[name]
+	if !ok {
+		fmt.Println("No such profile:", name)
+		return
+	}
+
+	switch field {
+	case "config":
+		profile.Config = value
+	case "extra_args":
+		profile.ExtraArgs = strings.Fields(value)
+	case "creds_file":
+		profile.CredsFile = value
+	case "killswitch":
+		profile.Killswitch = value == "true" || value == "on"
+	case "up_script":
+		profile.UpScript = value
+	case "down_script":
+		profile.DownScript = value
+	case "expected_remote":
+		profile.ExpectedRemote = value
+	default:
+		fmt.Println("Unknown field:", field)
+		return
+	}
+
+	cfg.Profiles[name] = profile
+	if err := cfg.save(configPath); err != nil {
+		fmt.Println("Failed to save config.toml:", err)
+		return
+	}
+	fmt.Printf("Updated %s.%s\n", name, field)
+}
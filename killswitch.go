@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// killswitchBackup stores the nftables ruleset that was active before zvpn
+// installed its own, so it can be restored on a clean stop.
+const killswitchBackup = "/tmp/zvpn.killswitch.nft"
+
+// killswitchPersistFlag marks that rules should survive an unexpected
+// supervisor exit instead of being torn down.
+const killswitchPersistFlag = "/tmp/zvpn.killswitch.persist"
+
+// killswitchEnabledFlag gates whether the supervisor installs kill-switch
+// rules at all once the tunnel comes up. Off by default, same as the old
+// behavior before this subsystem existed.
+const killswitchEnabledFlag = "/tmp/zvpn.killswitch.enabled"
+
+var tunDeviceRe = regexp.MustCompile(`device (tun\d+|tap\d+) opened`)
+var dnsOptionRe = regexp.MustCompile(`dhcp-option DNS ([0-9.]+)`)
+
+// killswitchCommand implements `zvpn --killswitch on|off|persist`.
+func killswitchCommand(mode string) {
+	switch mode {
+	case "on":
+		if err := os.WriteFile(killswitchEnabledFlag, []byte("1"), 0644); err != nil {
+			fmt.Println("Failed to enable kill-switch:", err)
+			return
+		}
+		fmt.Println("Kill-switch will be enabled on the next connection.")
+	case "off":
+		os.Remove(killswitchEnabledFlag)
+		if err := killswitchDisable(); err != nil {
+			fmt.Println("Failed to remove kill-switch rules:", err)
+			return
+		}
+		os.Remove(killswitchPersistFlag)
+		fmt.Println("Kill-switch disabled and rules removed.")
+	case "persist":
+		if err := os.WriteFile(killswitchPersistFlag, []byte("1"), 0644); err != nil {
+			fmt.Println("Failed to set persist flag:", err)
+			return
+		}
+		fmt.Println("Kill-switch rules will persist across crashes.")
+	default:
+		fmt.Println("Usage: zvpn --killswitch on|off|persist")
+	}
+}
+
+// killswitchEnable backs up the current nftables ruleset and installs one
+// that drops everything except traffic to the resolved remote endpoint(s)
+// on remotePort, the tun interface, the LAN, and outbound DNS. nftables
+// matches on IP addresses, not hostnames, so remoteHost is resolved first;
+// a config with several A/AAAA records gets one accept rule per address.
+// DNS (port 53) stays open on the pre-tunnel route so a hostname-based
+// remote can still be re-resolved on reconnect instead of wedging behind
+// the now-pinned address set.
+func killswitchEnable(remoteHost, remotePort, tunIface string) error {
+	addrs, err := net.LookupHost(remoteHost)
+	if err != nil {
+		return fmt.Errorf("failed to resolve remote %q: %w", remoteHost, err)
+	}
+
+	backup, err := exec.Command("nft", "list", "ruleset").Output()
+	if err != nil {
+		return fmt.Errorf("failed to snapshot existing ruleset: %w", err)
+	}
+	if err := os.WriteFile(killswitchBackup, backup, 0600); err != nil {
+		return err
+	}
+
+	var remoteRules strings.Builder
+	for _, addr := range addrs {
+		fmt.Fprintf(&remoteRules, "    ip daddr %s th dport %s accept\n", addr, remotePort)
+	}
+
+	ruleset := fmt.Sprintf(`
+table inet zvpn {
+  chain output {
+    type filter hook output priority 0; policy drop;
+    oifname "lo" accept
+    oifname "%s" accept
+%s    ip daddr { 10.0.0.0/8, 172.16.0.0/12, 192.168.0.0/16 } accept
+    udp dport 53 accept
+    tcp dport 53 accept
+  }
+}
+`, tunIface, remoteRules.String())
+
+	cmd := exec.Command("nft", "-f", "-")
+	cmd.Stdin = strings.NewReader(ruleset)
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// killswitchDisable removes zvpn's table and restores whatever ruleset was
+// active before it ran, unless the caller wants to persist rules.
+func killswitchDisable() error {
+	exec.Command("nft", "delete", "table", "inet", "zvpn").Run()
+
+	backup, err := os.ReadFile(killswitchBackup)
+	if err != nil {
+		return nil // nothing to restore
+	}
+	cmd := exec.Command("nft", "-f", "-")
+	cmd.Stdin = strings.NewReader(string(backup))
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+	return os.Remove(killswitchBackup)
+}
+
+// killswitchPersisted reports whether rules should be left in place after
+// an unexpected supervisor exit.
+func killswitchPersisted() bool {
+	_, err := os.Stat(killswitchPersistFlag)
+	return err == nil
+}
+
+// killswitchEnabled reports whether the supervisor should install rules at
+// all for the current connection.
+func killswitchEnabled() bool {
+	_, err := os.Stat(killswitchEnabledFlag)
+	return err == nil
+}
+
+// parseRemote reads the first "remote <host> [port]" directive out of an
+// .ovpn config, defaulting the port to 1194 as openvpn itself does.
+func parseRemote(config string) (host, port string, err error) {
+	data, err := os.ReadFile(config)
+	if err != nil {
+		return "", "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "remote" {
+			host = fields[1]
+			port = "1194"
+			if len(fields) >= 3 {
+				port = fields[2]
+			}
+			return host, port, nil
+		}
+	}
+	return "", "", fmt.Errorf("no 'remote' directive found in %s", config)
+}
+
+// tunIfaceFromLog scans a raw ">LOG:" management line for the tun/tap
+// device name openvpn just opened.
+func tunIfaceFromLog(raw string) string {
+	match := tunDeviceRe.FindStringSubmatch(raw)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+// pushedDNSFromLog scans a raw ">LOG:" management line for DNS servers
+// announced via a PUSH_REPLY, e.g. "dhcp-option DNS 1.1.1.1".
+func pushedDNSFromLog(raw string) []string {
+	matches := dnsOptionRe.FindAllStringSubmatch(raw, -1)
+	var servers []string
+	for _, m := range matches {
+		servers = append(servers, m[1])
+	}
+	return servers
+}
+
+// applyDNS points systemd-resolved at the pushed DNS servers for tunIface.
+func applyDNS(tunIface string, servers []string) error {
+	if len(servers) == 0 {
+		return nil
+	}
+	args := append([]string{"dns", tunIface}, servers...)
+	cmd := exec.Command("resolvectl", args...)
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// revertDNS clears any DNS servers zvpn set for tunIface.
+func revertDNS(tunIface string) error {
+	if tunIface == "" {
+		return nil
+	}
+	cmd := exec.Command("resolvectl", "revert", tunIface)
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseRemote(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		wantHost string
+		wantPort string
+		wantErr  bool
+	}{
+		{
+			name:     "host and port given",
+			config:   "client\nremote vpn.example.com 443\ndev tun\n",
+			wantHost: "vpn.example.com",
+			wantPort: "443",
+		},
+		{
+			name:     "port defaults to 1194",
+			config:   "client\nremote 203.0.113.5\ndev tun\n",
+			wantHost: "203.0.113.5",
+			wantPort: "1194",
+		},
+		{
+			name:    "no remote directive",
+			config:  "client\ndev tun\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.ovpn")
+			if err := os.WriteFile(path, []byte(tt.config), 0600); err != nil {
+				t.Fatal(err)
+			}
+			host, port, err := parseRemote(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRemote() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if host != tt.wantHost || port != tt.wantPort {
+				t.Errorf("parseRemote() = (%q, %q), want (%q, %q)", host, port, tt.wantHost, tt.wantPort)
+			}
+		})
+	}
+}
+
+func TestTunIfaceFromLog(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{name: "tun device", raw: ">LOG:1700000000,,device tun0 opened", want: "tun0"},
+		{name: "tap device", raw: ">LOG:1700000000,,device tap1 opened", want: "tap1"},
+		{name: "no match", raw: ">LOG:1700000000,,Initialization Sequence Completed", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tunIfaceFromLog(tt.raw); got != tt.want {
+				t.Errorf("tunIfaceFromLog(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPushedDNSFromLog(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want []string
+	}{
+		{
+			name: "single server",
+			raw:  ">LOG:1700000000,,PUSH: Received control message: 'PUSH_REPLY,dhcp-option DNS 1.1.1.1,route-gateway 10.8.0.1'",
+			want: []string{"1.1.1.1"},
+		},
+		{
+			name: "multiple servers",
+			raw:  ">LOG:1700000000,,PUSH: Received control message: 'PUSH_REPLY,dhcp-option DNS 1.1.1.1,dhcp-option DNS 8.8.8.8'",
+			want: []string{"1.1.1.1", "8.8.8.8"},
+		},
+		{
+			name: "no DNS option",
+			raw:  ">LOG:1700000000,,PUSH: Received control message: 'PUSH_REPLY,route-gateway 10.8.0.1'",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pushedDNSFromLog(tt.raw)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pushedDNSFromLog(%q) = %v, want %v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
@@ -4,18 +4,14 @@ import (
 	"bufio"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"strconv"
 	"strings"
-	"syscall"
 )
 
 const configDir = ".zvpn"
-const lastConfigFile = ".last_config"
-const logFile = "/tmp/zvpn.log"
-const pidFile = "/tmp/zvpn.pid"
 
 func main() {
 	if os.Geteuid() != 0 {
@@ -23,11 +19,6 @@ func main() {
 		return
 	}
 
-	if !isOpenVPNInstalled() {
-		fmt.Println("OpenVPN is not installed on your system. Please install it first.")
-		return
-	}
-
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		fmt.Println("Failed to get home directory:", err)
@@ -53,22 +44,57 @@ func main() {
 		}
 	}
 
+	if _, err := loadZvpnConfig(configPath); err != nil {
+		fmt.Println("Failed to read config.toml:", err)
+		return
+	}
+
 	if len(os.Args) < 2 {
-		stopServiceIfNeeded()
+		stopServiceIfNeeded(configPath)
 		startWithPrompt(configPath)
 	} else {
 		switch os.Args[1] {
+		case "--supervise":
+			// Internal: re-exec'd in the background by startService to run
+			// the supervisor loop in the foreground of a detached process.
+			if len(os.Args) < 4 {
+				fmt.Println("--supervise requires a config dir and profile name")
+				return
+			}
+			runSupervisor(os.Args[2], os.Args[3])
 		case "--start":
-			stopServiceIfNeeded()
-			startLastUsedConfig(configPath)
+			stopServiceIfNeeded(configPath)
+			profileName := ""
+			if len(os.Args) >= 3 {
+				profileName = os.Args[2]
+			}
+			startProfile(configPath, profileName)
 		case "--stop":
-			stopService()
+			stopService(configPath)
 		case "--status":
-			showStatus()
+			showStatus(configPath)
+		case "--events":
+			showEvents()
 		case "--log":
 			showLog()
+		case "--login":
+			if len(os.Args) < 3 {
+				fmt.Println("--login requires a configuration name")
+				return
+			}
+			loginCommand(configPath, os.Args[2])
+		case "--killswitch":
+			if len(os.Args) < 3 {
+				fmt.Println("Usage: zvpn --killswitch on|off|persist")
+				return
+			}
+			killswitchCommand(os.Args[2])
+		case "--reload":
+			reloadService(configPath)
+		case "profiles":
+			profilesCommand(configPath, os.Args[2:])
 		default:
-			fmt.Println("Unknown argument. Use --start, --stop, --status, or --log.")
+			fmt.Println("Unknown argument. Use --start [profile], --stop, --status, --events, --log, --login <config>, --killswitch on|off|persist, --reload, or profiles list|add|remove|edit.")
 		}
 	}
 }
@@ -79,27 +105,20 @@ func isOpenVPNInstalled() bool {
 }
 
 func startWithPrompt(configPath string) {
-	files, err := os.ReadDir(configPath)
+	names, backends, err := listBackendConfigs(configPath)
 	if err != nil {
 		fmt.Println("Failed to read configuration directory:", err)
 		return
 	}
 
-	var configs []string
-	for _, file := range files {
-		if strings.HasSuffix(file.Name(), ".ovpn") {
-			configs = append(configs, file.Name())
-		}
-	}
-
-	if len(configs) == 0 {
-		fmt.Println("No valid ovpn config files found in", configPath)
+	if len(names) == 0 {
+		fmt.Println("No .ovpn or .conf config files found in", configPath)
 		return
 	}
 
 	fmt.Println("Select a configuration file to use:")
-	for i, config := range configs {
-		fmt.Printf("%d. %s\n", i+1, config)
+	for i, name := range names {
+		fmt.Printf("%d. %s [%s]\n", i+1, name, backends[i])
 	}
 
 	reader := bufio.NewReader(os.Stdin)
@@ -109,104 +128,176 @@ func startWithPrompt(configPath string) {
 	index := 0
 
 	fmt.Sscanf(choice, "%d", &index)
-	if index < 1 || index > len(configs) {
+	if index < 1 || index > len(names) {
 		fmt.Println("Invalid choice")
 		return
 	}
 
-	selectedConfig := configs[index-1]
-	saveLastUsedConfig(configPath, selectedConfig)
-	startService(filepath.Join(configPath, selectedConfig))
+	selectedConfig := names[index-1]
+	cfg, err := loadZvpnConfig(configPath)
+	if err != nil {
+		fmt.Println("Failed to read config.toml:", err)
+		return
+	}
+	// Record the selection as a profile so the re-exec'd supervisor (which
+	// resolves profiles by name from config.toml, not from our argv) can
+	// find it, but leave default_profile alone: this is a one-off pick for
+	// this run, not a change to what `zvpn --start` should do next time.
+	cfg.Profiles["adhoc"] = Profile{Config: selectedConfig, Backend: backends[index-1]}
+	if err := cfg.save(configPath); err != nil {
+		fmt.Println("Failed to save config.toml:", err)
+		return
+	}
+
+	runStartService(configPath, "adhoc", cfg.Profiles["adhoc"])
 }
 
-func saveLastUsedConfig(configPath, configName string) {
-	err := os.WriteFile(filepath.Join(configPath, lastConfigFile), []byte(configName), 0644)
+// startProfile resolves profileName (or the configured default) to a
+// Profile and starts the supervisor for it.
+func startProfile(configPath, profileName string) {
+	cfg, err := loadZvpnConfig(configPath)
 	if err != nil {
-		fmt.Println("Failed to save last used configuration:", err)
+		fmt.Println("Failed to read config.toml:", err)
+		return
 	}
-}
 
-func startLastUsedConfig(configPath string) {
-	lastConfig, err := os.ReadFile(filepath.Join(configPath, lastConfigFile))
+	profile, name, err := cfg.resolveProfile(profileName)
 	if err != nil {
-		fmt.Println("Failed to read last used configuration:", err)
+		fmt.Println(err)
 		return
 	}
 
-	startService(filepath.Join(configPath, strings.TrimSpace(string(lastConfig))))
+	runStartService(configPath, name, profile)
 }
 
-func startService(config string) {
-	cmd := exec.Command("sudo", "openvpn", "--config", config)
-	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+// runStartService validates a profile and hands it off to the right
+// Backend (OpenVPN or WireGuard).
+func runStartService(configPath, profileName string, profile Profile) {
+	backend, config := backendForProfile(configPath, profileName, profile)
 
-	logFile, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-	if err != nil {
-		fmt.Println("Failed to open log file:", err)
+	if _, ok := backend.(*openvpnBackend); ok && !isOpenVPNInstalled() {
+		fmt.Println("OpenVPN is not installed on your system. Please install it first.")
 		return
 	}
-	defer logFile.Close()
-	cmd.Stdout = logFile
-	cmd.Stderr = logFile
 
-	if err := cmd.Start(); err != nil {
+	if profile.ExpectedRemote != "" {
+		host, err := remoteHost(backend, config)
+		if err != nil {
+			fmt.Println("Failed to verify remote:", err)
+			return
+		}
+		if host != profile.ExpectedRemote {
+			fmt.Printf("Refusing to start: config remote %q does not match expected_remote %q\n", host, profile.ExpectedRemote)
+			return
+		}
+	}
+
+	if profile.Killswitch {
+		killswitchCommand("on")
+	} else {
+		killswitchCommand("off")
+	}
+
+	if err := backend.Start(); err != nil {
 		fmt.Println("Failed to start service:", err)
 		return
 	}
+	fmt.Println("VPN starting with profile:", profileName)
+}
 
-	err = os.WriteFile(pidFile, []byte(strconv.Itoa(cmd.Process.Pid)), 0644)
-	if err != nil {
-		fmt.Println("Failed to save PID:", err)
-		return
+// remoteHost returns the remote host an expected_remote check should
+// compare against: the config's "remote" directive for OpenVPN, or the
+// first peer's endpoint host for WireGuard, since parseRemote only
+// understands .ovpn syntax.
+func remoteHost(backend Backend, config string) (string, error) {
+	if _, ok := backend.(*wireguardBackend); ok {
+		conf, err := parseWireGuardConf(config)
+		if err != nil {
+			return "", err
+		}
+		if len(conf.Peers) == 0 {
+			return "", fmt.Errorf("no peers found in %s", config)
+		}
+		host, _, err := net.SplitHostPort(conf.Peers[0].Endpoint)
+		return host, err
 	}
+	host, _, err := parseRemote(config)
+	return host, err
+}
 
-	fmt.Println("VPN started with configuration:", config)
+// currentBackend resolves whichever backend is recorded as active so
+// --stop/--status/--reload can address it without the caller having to
+// know which backend started it.
+func currentBackend(configPath string) (Backend, error) {
+	kind, detail, err := readActiveBackend()
+	if err != nil {
+		return nil, fmt.Errorf("VPN service is not running")
+	}
+	if kind == backendWireGuard {
+		return &wireguardBackend{confPath: detail}, nil
+	}
+	return &openvpnBackend{configPath: configPath, profileName: detail}, nil
 }
 
-func stopServiceIfNeeded() {
-	if _, err := os.Stat(pidFile); err == nil {
+func stopServiceIfNeeded(configPath string) {
+	if _, _, err := readActiveBackend(); err == nil {
 		fmt.Println("An active VPN connection is detected. Stopping it before starting a new one.")
-		stopService()
+		stopService(configPath)
 	}
 }
 
-func stopService() {
-	cmd := exec.Command("sudo", "pkill", "openvpn")
-	if err := cmd.Run(); err != nil {
-		fmt.Println("Failed to stop the VPN service:", err)
+func stopService(configPath string) {
+	backend, err := currentBackend(configPath)
+	if err != nil {
+		fmt.Println("VPN service is not running.")
 		return
 	}
-
-	if err := os.Remove(pidFile); err != nil {
-		fmt.Println("Failed to remove PID file:", err)
+	if err := backend.Stop(); err != nil {
+		fmt.Println("Failed to stop the VPN service:", err)
+		return
 	}
-
 	fmt.Println("VPN service stopped.")
 }
 
-func showStatus() {
-	pidData, err := os.ReadFile(pidFile)
+func showStatus(configPath string) {
+	backend, err := currentBackend(configPath)
 	if err != nil {
 		fmt.Println("VPN service is not running.")
 		return
 	}
-
-	pid, err := strconv.Atoi(strings.TrimSpace(string(pidData)))
+	status, err := backend.Status()
 	if err != nil {
-		fmt.Println("Invalid PID in PID file.")
+		fmt.Println(err)
 		return
 	}
+	fmt.Println(status)
+}
 
-	process, err := os.FindProcess(pid)
+func reloadService(configPath string) {
+	backend, err := currentBackend(configPath)
 	if err != nil {
 		fmt.Println("VPN service is not running.")
 		return
 	}
+	if err := backend.Reload(); err != nil {
+		fmt.Println("Failed to reload:", err)
+		return
+	}
+	fmt.Println("VPN service reloaded.")
+}
 
-	if err := process.Signal(syscall.Signal(0)); err != nil {
+func showEvents() {
+	conn, err := connectControl()
+	if err != nil {
 		fmt.Println("VPN service is not running.")
-	} else {
-		fmt.Println("VPN service is running.")
+		return
+	}
+	defer conn.Close()
+
+	fmt.Fprintln(conn, "EVENTS")
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		fmt.Println(scanner.Text())
 	}
 }
 
@@ -219,4 +310,3 @@ func showLog() {
 	fmt.Println("VPN Logs:")
 	fmt.Println(string(logData))
 }
-
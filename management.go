@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// managementPort is the local TCP port the supervisor asks openvpn to bind
+// its management interface to. It never leaves 127.0.0.1.
+const managementPort = 17505
+
+// TunnelState is one of the high-level states openvpn reports while
+// negotiating or holding a connection.
+type TunnelState string
+
+const (
+	StateUnknown      TunnelState = "UNKNOWN"
+	StateConnecting   TunnelState = "CONNECTING"
+	StateWait         TunnelState = "WAIT"
+	StateAuth         TunnelState = "AUTH"
+	StateReconnecting TunnelState = "RECONNECTING"
+	StateConnected    TunnelState = "CONNECTED"
+	StateExiting      TunnelState = "EXITING"
+)
+
+// TunnelStatus is the latest snapshot derived from management "state" and
+// "bytecount" events.
+type TunnelStatus struct {
+	State     TunnelState
+	RemoteIP  string
+	VirtualIP string
+	RxBytes   int64
+	TxBytes   int64
+	Updated   time.Time
+}
+
+// ManagementEvent is a single parsed line read off the management socket,
+// either a real-time ">..." push or a reply to a command we issued.
+type ManagementEvent struct {
+	Kind string // "state", "bytecount", "log", "password", "reply"
+	Raw  string
+}
+
+// managementClient speaks the line-oriented OpenVPN management protocol
+// described in openvpn's management-notes.txt.
+type managementClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dialManagement(port int) (*managementClient, error) {
+	conn, err := net.DialTimeout("tcp", fmt.Sprintf("127.0.0.1:%d", port), 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return &managementClient{conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+func (c *managementClient) close() {
+	c.conn.Close()
+}
+
+func (c *managementClient) send(cmd string) error {
+	_, err := fmt.Fprintf(c.conn, "%s\n", cmd)
+	return err
+}
+
+func (c *managementClient) readLine() (string, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// subscribe asks the daemon to hold for our "hold release" and to start
+// streaming state, bytecount, and log events.
+func (c *managementClient) subscribe() error {
+	if err := c.send("state on"); err != nil {
+		return err
+	}
+	if err := c.send("bytecount 5"); err != nil {
+		return err
+	}
+	if err := c.send("log on"); err != nil {
+		return err
+	}
+	return c.send("hold release")
+}
+
+// parseEvent classifies a raw management line into an ManagementEvent and,
+// for state/bytecount lines, applies it to status.
+func parseEvent(raw string, status *TunnelStatus) ManagementEvent {
+	switch {
+	case strings.HasPrefix(raw, ">STATE:"):
+		applyStateEvent(raw, status)
+		return ManagementEvent{Kind: "state", Raw: raw}
+	case strings.HasPrefix(raw, ">BYTECOUNT:"):
+		applyByteCountEvent(raw, status)
+		return ManagementEvent{Kind: "bytecount", Raw: raw}
+	case strings.HasPrefix(raw, ">LOG:"):
+		return ManagementEvent{Kind: "log", Raw: raw}
+	case strings.HasPrefix(raw, ">PASSWORD:"):
+		return ManagementEvent{Kind: "password", Raw: raw}
+	default:
+		return ManagementEvent{Kind: "reply", Raw: raw}
+	}
+}
+
+// applyStateEvent parses ">STATE:<unix-ts>,<state>,<detail>,<local-ip>,<remote-ip>,..."
+func applyStateEvent(raw string, status *TunnelStatus) {
+	fields := strings.Split(strings.TrimPrefix(raw, ">STATE:"), ",")
+	if len(fields) < 2 {
+		return
+	}
+	status.State = TunnelState(fields[1])
+	if len(fields) >= 4 && fields[3] != "" {
+		status.VirtualIP = fields[3]
+	}
+	if len(fields) >= 5 && fields[4] != "" {
+		status.RemoteIP = fields[4]
+	}
+	status.Updated = time.Now()
+}
+
+// applyByteCountEvent parses ">BYTECOUNT:<rx>,<tx>"
+func applyByteCountEvent(raw string, status *TunnelStatus) {
+	fields := strings.Split(strings.TrimPrefix(raw, ">BYTECOUNT:"), ",")
+	if len(fields) != 2 {
+		return
+	}
+	if rx, err := strconv.ParseInt(fields[0], 10, 64); err == nil {
+		status.RxBytes = rx
+	}
+	if tx, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+		status.TxBytes = tx
+	}
+	status.Updated = time.Now()
+}
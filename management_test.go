@@ -0,0 +1,72 @@
+package main
+
+import "testing"
+
+func TestApplyStateEvent(t *testing.T) {
+	tests := []struct {
+		name      string
+		raw       string
+		wantState TunnelState
+		wantVIP   string
+		wantRIP   string
+	}{
+		{
+			name:      "connected with addresses",
+			raw:       ">STATE:1700000000,CONNECTED,SUCCESS,10.8.0.2,203.0.113.5,1194,,",
+			wantState: StateConnected,
+			wantVIP:   "10.8.0.2",
+			wantRIP:   "203.0.113.5",
+		},
+		{
+			name:      "connecting with no addresses yet",
+			raw:       ">STATE:1700000000,CONNECTING,,,",
+			wantState: StateConnecting,
+		},
+		{
+			name:      "too few fields is ignored",
+			raw:       ">STATE:1700000000",
+			wantState: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := &TunnelStatus{}
+			applyStateEvent(tt.raw, status)
+			if status.State != tt.wantState {
+				t.Errorf("State = %q, want %q", status.State, tt.wantState)
+			}
+			if status.VirtualIP != tt.wantVIP {
+				t.Errorf("VirtualIP = %q, want %q", status.VirtualIP, tt.wantVIP)
+			}
+			if status.RemoteIP != tt.wantRIP {
+				t.Errorf("RemoteIP = %q, want %q", status.RemoteIP, tt.wantRIP)
+			}
+		})
+	}
+}
+
+func TestApplyByteCountEvent(t *testing.T) {
+	tests := []struct {
+		name   string
+		raw    string
+		wantRx int64
+		wantTx int64
+	}{
+		{name: "valid counts", raw: ">BYTECOUNT:1024,2048", wantRx: 1024, wantTx: 2048},
+		{name: "malformed is ignored", raw: ">BYTECOUNT:not-a-number", wantRx: 0, wantTx: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := &TunnelStatus{RxBytes: 0, TxBytes: 0}
+			applyByteCountEvent(tt.raw, status)
+			if status.RxBytes != tt.wantRx {
+				t.Errorf("RxBytes = %d, want %d", status.RxBytes, tt.wantRx)
+			}
+			if status.TxBytes != tt.wantTx {
+				t.Errorf("TxBytes = %d, want %d", status.TxBytes, tt.wantTx)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// metrics accumulates the counters and gauges exposed at /metrics. Byte
+// counts and reconnects need to keep climbing across openvpn restarts even
+// though openvpn itself reports bytecount relative to the current session.
+type metrics struct {
+	reconnects int64
+	priorRx    int64
+	priorTx    int64
+	pingMs     float64
+}
+
+var pingTimeRe = regexp.MustCompile(`time[=<]([0-9.]+) ?ms`)
+
+func (m *metrics) rxTotal(status TunnelStatus) int64 { return m.priorRx + status.RxBytes }
+func (m *metrics) txTotal(status TunnelStatus) int64 { return m.priorTx + status.TxBytes }
+
+// pingGateway runs a single system ping against the tunnel remote and
+// records the round-trip time, the same way the rest of zvpn shells out to
+// openvpn/nft/resolvectl rather than speaking raw ICMP sockets itself.
+func pingGateway(host string) (float64, error) {
+	out, err := exec.Command("ping", "-c", "1", "-W", "1", host).Output()
+	if err != nil {
+		return 0, err
+	}
+	match := pingTimeRe.FindStringSubmatch(string(out))
+	if match == nil {
+		return 0, fmt.Errorf("could not parse ping output")
+	}
+	return strconv.ParseFloat(match[1], 64)
+}
+
+// runPingLoop periodically updates s.metrics.pingMs while the tunnel is
+// connected. It's started once per supervisor lifetime, not per connection,
+// so it simply no-ops when there's nothing to ping yet.
+func (s *supervisor) runPingLoop() {
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		if s.isStopping() {
+			return
+		}
+		status := s.statusSnapshot()
+		if status.State != StateConnected || status.RemoteIP == "" {
+			continue
+		}
+		if ms, err := pingGateway(status.RemoteIP); err == nil {
+			s.mu.Lock()
+			s.metrics.pingMs = ms
+			s.mu.Unlock()
+		}
+	}
+}
+
+// renderPrometheus formats the current metrics in Prometheus exposition
+// format for /metrics.
+func (s *supervisor) renderPrometheus() string {
+	status := s.statusSnapshot()
+	s.mu.Lock()
+	m := s.metrics
+	s.mu.Unlock()
+
+	connected := 0
+	if status.State == StateConnected {
+		connected = 1
+	}
+
+	return fmt.Sprintf(
+		"# HELP zvpn_bytes_rx_total Bytes received over the tunnel.\n"+
+			"# TYPE zvpn_bytes_rx_total counter\n"+
+			"zvpn_bytes_rx_total %d\n"+
+			"# HELP zvpn_bytes_tx_total Bytes transmitted over the tunnel.\n"+
+			"# TYPE zvpn_bytes_tx_total counter\n"+
+			"zvpn_bytes_tx_total %d\n"+
+			"# HELP zvpn_reconnects_total Number of times openvpn has been restarted by the supervisor.\n"+
+			"# TYPE zvpn_reconnects_total counter\n"+
+			"zvpn_reconnects_total %d\n"+
+			"# HELP zvpn_connected Whether the tunnel is currently connected.\n"+
+			"# TYPE zvpn_connected gauge\n"+
+			"zvpn_connected %d\n"+
+			"# HELP zvpn_ping_ms Last measured round-trip time to the VPN remote, in milliseconds.\n"+
+			"# TYPE zvpn_ping_ms gauge\n"+
+			"zvpn_ping_ms %f\n"+
+			"# HELP zvpn_state Current tunnel state (%s).\n"+
+			"# TYPE zvpn_state gauge\n"+
+			"zvpn_state{state=%q} 1\n",
+		m.rxTotal(status), m.txTotal(status), m.reconnects,
+		connected, m.pingMs, status.State, string(status.State),
+	)
+}
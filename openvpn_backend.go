@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// openvpnBackend drives a tunnel through the detached supervisor process
+// (see supervisor.go), talking to it over controlSocket once it's up.
+type openvpnBackend struct {
+	configPath  string
+	profileName string
+	config      string
+	credsFile   string
+}
+
+func (b *openvpnBackend) Start() error {
+	if err := ensureCredentialsCached(b.configPath, b.config, b.credsFile); err != nil {
+		return err
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate zvpn binary: %w", err)
+	}
+
+	cmd := exec.Command(self, "--supervise", b.configPath, b.profileName)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	devnull, err := os.OpenFile(os.DevNull, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open /dev/null: %w", err)
+	}
+	defer devnull.Close()
+	cmd.Stdin = devnull
+	cmd.Stdout = devnull
+	cmd.Stderr = devnull
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start service: %w", err)
+	}
+
+	return writeActiveBackend(backendOpenVPN, b.profileName)
+}
+
+func (b *openvpnBackend) Stop() error {
+	reply, err := dialControl("STOP")
+	if err != nil {
+		// The supervisor may have died without cleaning up after itself; if
+		// so, drop the stale activeBackendFile and control socket too so
+		// --status/--start don't stay wedged believing a tunnel is up.
+		os.Remove(controlSocket)
+		clearActiveBackend()
+		return fmt.Errorf("VPN service is not running")
+	}
+	if reply != "OK" {
+		return fmt.Errorf("failed to stop the VPN service: %s", reply)
+	}
+	clearActiveBackend()
+	return nil
+}
+
+func (b *openvpnBackend) Status() (string, error) {
+	reply, err := dialControl("STATUS")
+	if err != nil {
+		return "", fmt.Errorf("VPN service is not running")
+	}
+
+	fields := strings.Split(reply, "|")
+	if len(fields) != 5 {
+		return "", fmt.Errorf("VPN service returned an unexpected status")
+	}
+
+	return fmt.Sprintf("Backend:    openvpn\nState:      %s\nRemote IP:  %s\nVirtual IP: %s\nRX bytes:   %s\nTX bytes:   %s",
+		fields[0], fields[1], fields[2], fields[3], fields[4]), nil
+}
+
+func (b *openvpnBackend) Reload() error {
+	reply, err := dialControl("RELOAD")
+	if err != nil {
+		return fmt.Errorf("VPN service is not running")
+	}
+	if reply != "OK" {
+		return fmt.Errorf("failed to reload: %s", reply)
+	}
+	return nil
+}
@@ -0,0 +1,475 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// passwdFile holds the one-off management interface password the supervisor
+// generates for each run so the TCP port isn't left unauthenticated.
+const passwdFile = "/tmp/zvpn.mgmt.passwd"
+
+const (
+	minBackoff = 1 * time.Second
+	maxBackoff = 30 * time.Second
+)
+
+// supervisor owns the openvpn child process, its management connection, and
+// the control socket used to answer --stop/--status/--events.
+//
+// status, cmd, mgmt, stopping and metrics are read and written from several
+// goroutines (the event-reading loop, control-socket handlers, the ping
+// loop, and the HTTP handlers), so all access to them must go through mu.
+// The remaining fields are only ever touched from the supervisor's own
+// run loop and don't need locking.
+type supervisor struct {
+	mu sync.Mutex
+
+	config     string
+	configPath string
+	credsFile  string
+	extraArgs  []string
+	upScript   string
+	downScript string
+	status     TunnelStatus
+	stopping   bool
+	events     chan ManagementEvent
+	tunIface   string
+	pushedDNS  []string
+	fwUp       bool
+	upRan      bool
+	cmd        *exec.Cmd
+	mgmt       *managementClient
+	metrics    metrics
+}
+
+// isStopping reports whether the supervisor has been asked to shut down.
+func (s *supervisor) isStopping() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stopping
+}
+
+// setStopping records that the supervisor should shut down instead of
+// restarting openvpn after the current process exits.
+func (s *supervisor) setStopping(v bool) {
+	s.mu.Lock()
+	s.stopping = v
+	s.mu.Unlock()
+}
+
+// setCmd records (or clears, when cmd is nil) the currently running openvpn
+// process.
+func (s *supervisor) setCmd(cmd *exec.Cmd) {
+	s.mu.Lock()
+	s.cmd = cmd
+	s.mu.Unlock()
+}
+
+// killCmd signals the currently running openvpn process, if any, the same
+// way STOP and the HTTP control endpoints force a reconnect or shutdown.
+func (s *supervisor) killCmd() {
+	s.mu.Lock()
+	cmd := s.cmd
+	s.mu.Unlock()
+	if cmd != nil {
+		cmd.Process.Kill()
+	}
+}
+
+// setMgmt records (or clears, when mgmt is nil) the current management
+// connection, so RELOAD can reach it from the control-socket goroutine.
+func (s *supervisor) setMgmt(mgmt *managementClient) {
+	s.mu.Lock()
+	s.mgmt = mgmt
+	s.mu.Unlock()
+}
+
+// statusSnapshot returns a copy of the current tunnel status, safe to read
+// without holding mu.
+func (s *supervisor) statusSnapshot() TunnelStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// runSupervisor is the entry point used by the re-exec'd background process
+// (see startService). It resolves profileName out of configPath's
+// config.toml itself, rather than trusting a fully-expanded command line,
+// and never returns while the tunnel should stay up.
+func runSupervisor(configPath, profileName string) {
+	cfg, err := loadZvpnConfig(configPath)
+	if err != nil {
+		fmt.Println("Failed to read config.toml:", err)
+		return
+	}
+	profile, _, err := cfg.resolveProfile(profileName)
+	if err != nil {
+		fmt.Println("Failed to resolve profile:", err)
+		return
+	}
+
+	config := profile.Config
+	if !filepath.IsAbs(config) {
+		config = filepath.Join(configPath, config)
+	}
+
+	s := &supervisor{
+		config:     config,
+		configPath: configPath,
+		credsFile:  profile.CredsFile,
+		extraArgs:  profile.ExtraArgs,
+		upScript:   profile.UpScript,
+		downScript: profile.DownScript,
+		events:     make(chan ManagementEvent, 64),
+	}
+
+	listener, err := net.Listen("unix", controlSocket)
+	if err != nil {
+		fmt.Println("Failed to open control socket:", err)
+		return
+	}
+	defer listener.Close()
+	defer os.Remove(controlSocket)
+	go s.serveControl(listener)
+	go s.runPingLoop()
+
+	if cfg.HTTP.Enabled {
+		go s.serveHTTP(cfg.HTTP.Listen, configPath)
+	}
+
+	backoff := minBackoff
+	for {
+		if s.isStopping() {
+			return
+		}
+		started := time.Now()
+		if err := s.runOnce(); err != nil {
+			fmt.Println("openvpn exited:", err)
+		}
+		if s.isStopping() {
+			return
+		}
+		s.mu.Lock()
+		s.metrics.reconnects++
+		s.mu.Unlock()
+		if time.Since(started) > maxBackoff {
+			backoff = minBackoff
+		}
+		fmt.Printf("openvpn exited unexpectedly, restarting in %s\n", backoff)
+		time.Sleep(backoff)
+		if backoff < maxBackoff {
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+	}
+}
+
+// runOnce launches a single openvpn process, attaches to its management
+// interface, and blocks until it exits.
+func (s *supervisor) runOnce() error {
+	s.upRan = false
+
+	if err := writeManagementPasswd(); err != nil {
+		return err
+	}
+	defer os.Remove(passwdFile)
+
+	args := []string{
+		"--config", s.config,
+		"--management", "127.0.0.1", strconv.Itoa(managementPort), passwdFile,
+		"--management-hold",
+		"--management-query-passwords",
+	}
+	args = append(args, s.extraArgs...)
+	cmd := exec.Command("openvpn", args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	logFile, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	s.setCmd(cmd)
+	defer s.setCmd(nil)
+
+	mgmt, err := waitForManagement(managementPort)
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return err
+	}
+	defer mgmt.close()
+	s.setMgmt(mgmt)
+	defer s.setMgmt(nil)
+
+	if err := authenticateManagement(mgmt); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return err
+	}
+
+	if err := mgmt.subscribe(); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	s.readEvents(mgmt, done)
+	exitErr := <-done
+	s.teardownFirewall()
+	if s.upRan {
+		runHookScript(s.downScript)
+		s.upRan = false
+	}
+	s.mu.Lock()
+	s.metrics.priorRx += s.status.RxBytes
+	s.metrics.priorTx += s.status.TxBytes
+	s.status.RxBytes = 0
+	s.status.TxBytes = 0
+	s.mu.Unlock()
+	s.tunIface = ""
+	s.pushedDNS = nil
+	return exitErr
+}
+
+func (s *supervisor) readEvents(mgmt *managementClient, done chan error) {
+	for {
+		select {
+		case err := <-done:
+			done <- err
+			return
+		default:
+		}
+		line, err := mgmt.readLine()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		event := parseEvent(line, &s.status)
+		justConnected := event.Kind == "state" && s.status.State == StateConnected
+		s.mu.Unlock()
+		if event.Kind == "password" {
+			handlePasswordRequest(mgmt, s.configPath, s.config, s.credsFile, event.Raw)
+		}
+		if event.Kind == "log" {
+			s.handleLogEvent(event.Raw)
+		}
+		if justConnected && !s.upRan {
+			runHookScript(s.upScript)
+			s.upRan = true
+		}
+		select {
+		case s.events <- event:
+		default:
+		}
+	}
+}
+
+// waitForManagement retries dialing the management port for a few seconds
+// while openvpn finishes binding it.
+func waitForManagement(port int) (*managementClient, error) {
+	var lastErr error
+	for i := 0; i < 50; i++ {
+		mgmt, err := dialManagement(port)
+		if err == nil {
+			return mgmt, nil
+		}
+		lastErr = err
+		time.Sleep(100 * time.Millisecond)
+	}
+	return nil, lastErr
+}
+
+// runHookScript runs a profile's up_script/down_script, if one is set,
+// logging failures rather than treating them as fatal to the tunnel.
+func runHookScript(script string) {
+	if script == "" {
+		return
+	}
+	if err := exec.Command("/bin/sh", "-c", script).Run(); err != nil {
+		fmt.Println("Hook script failed:", script, err)
+	}
+}
+
+func writeManagementPasswd() error {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	passwd := fmt.Sprintf("%x", buf)
+	return os.WriteFile(passwdFile, []byte(passwd+"\n"), 0600)
+}
+
+func authenticateManagement(mgmt *managementClient) error {
+	// openvpn prompts for the contents of passwdFile before it lets us send
+	// anything else; failing to answer (or mis-answering) the prompt gets
+	// the rest of our commands read as password guesses and the connection
+	// dropped with "ERROR: bad password".
+	passwd, err := os.ReadFile(passwdFile)
+	if err != nil {
+		return err
+	}
+	prompt, err := mgmt.readLine()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(prompt, "ENTER PASSWORD:") {
+		return fmt.Errorf("unexpected management greeting: %q", prompt)
+	}
+	if err := mgmt.send(strings.TrimSpace(string(passwd))); err != nil {
+		return err
+	}
+	reply, err := mgmt.readLine()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(reply, "SUCCESS:") {
+		return fmt.Errorf("management authentication failed: %q", reply)
+	}
+	return nil
+}
+
+// serveControl answers connections from `zvpn --stop` / `--status` /
+// `--events` with a single line reply (or, for --events, a streamed feed).
+func (s *supervisor) serveControl(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handleControl(conn)
+	}
+}
+
+func (s *supervisor) handleControl(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return
+	}
+	switch trimNewline(line) {
+	case "STATUS":
+		status := s.statusSnapshot()
+		fmt.Fprintf(conn, "%s|%s|%s|%d|%d\n", status.State, status.RemoteIP, status.VirtualIP, status.RxBytes, status.TxBytes)
+	case "STOP":
+		s.setStopping(true)
+		s.killCmd()
+		fmt.Fprintln(conn, "OK")
+	case "EVENTS":
+		for event := range s.events {
+			fmt.Fprintln(conn, event.Raw)
+		}
+	case "RELOAD":
+		s.mu.Lock()
+		mgmt := s.mgmt
+		s.mu.Unlock()
+		if mgmt == nil {
+			fmt.Fprintln(conn, "ERR not connected")
+			return
+		}
+		mgmt.send("signal SIGHUP")
+		fmt.Fprintln(conn, "OK")
+	default:
+		fmt.Fprintln(conn, "ERR unknown command")
+	}
+}
+
+func trimNewline(s string) string {
+	for len(s) > 0 && (s[len(s)-1] == '\n' || s[len(s)-1] == '\r') {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+// dialControl connects to a running supervisor's control socket and sends
+// a single command, returning its one-line reply.
+func dialControl(cmd string) (string, error) {
+	conn, err := connectControl()
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "%s\n", cmd)
+	reader := bufio.NewReader(conn)
+	reply, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return trimNewline(reply), nil
+}
+
+// connectControl opens a raw connection to the supervisor's control socket
+// for callers (like --events) that need to keep reading beyond one line.
+func connectControl() (net.Conn, error) {
+	return net.DialTimeout("unix", controlSocket, 2*time.Second)
+}
+
+// handleLogEvent watches raw ">LOG:" lines for the tun/tap device name and
+// any DNS servers the remote pushed, applying the kill-switch and DNS
+// pinning as soon as both are known.
+func (s *supervisor) handleLogEvent(raw string) {
+	if iface := tunIfaceFromLog(raw); iface != "" {
+		s.tunIface = iface
+		s.applyFirewall()
+		if len(s.pushedDNS) > 0 {
+			applyDNS(s.tunIface, s.pushedDNS)
+		}
+	}
+	if servers := pushedDNSFromLog(raw); len(servers) > 0 {
+		s.pushedDNS = servers
+		if s.tunIface != "" {
+			applyDNS(s.tunIface, s.pushedDNS)
+		}
+	}
+}
+
+// applyFirewall installs the kill-switch ruleset once the tun interface and
+// the config's remote are both known.
+func (s *supervisor) applyFirewall() {
+	if s.fwUp || s.tunIface == "" || !killswitchEnabled() {
+		return
+	}
+	host, port, err := parseRemote(s.config)
+	if err != nil {
+		fmt.Println("Kill-switch not applied, failed to read remote from config:", err)
+		return
+	}
+	if err := killswitchEnable(host, port, s.tunIface); err != nil {
+		fmt.Println("Failed to apply kill-switch rules:", err)
+		return
+	}
+	s.fwUp = true
+}
+
+// teardownFirewall restores the pre-zvpn firewall state and DNS settings,
+// unless the user asked for rules to persist across crashes.
+func (s *supervisor) teardownFirewall() {
+	if s.tunIface != "" {
+		revertDNS(s.tunIface)
+	}
+	if s.fwUp && !killswitchPersisted() {
+		killswitchDisable()
+		s.fwUp = false
+	}
+}
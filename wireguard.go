@@ -0,0 +1,283 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.zx2c4.com/wireguard/wgctrl"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// parseUDPEndpoint resolves a wg-quick "host:port" endpoint. Failures are
+// swallowed to nil, matching wgctrl's own semantics for "no endpoint yet".
+func parseUDPEndpoint(endpoint string) *net.UDPAddr {
+	addr, err := net.ResolveUDPAddr("udp", endpoint)
+	if err != nil {
+		return nil
+	}
+	return addr
+}
+
+// wireguardBackend drives a tunnel from a WireGuard .conf file. It prefers
+// wg-quick, the same tool most providers document, and only falls back to
+// programming the interface directly through wgctrl when wg-quick isn't
+// installed.
+type wireguardBackend struct {
+	confPath   string
+	upScript   string
+	downScript string
+}
+
+func (b *wireguardBackend) iface() string {
+	return strings.TrimSuffix(filepath.Base(b.confPath), ".conf")
+}
+
+func (b *wireguardBackend) Start() error {
+	if _, err := exec.LookPath("wg-quick"); err == nil {
+		cmd := exec.Command("wg-quick", "up", b.confPath)
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return err
+		}
+	} else if err := wireguardStartWithoutQuick(b.confPath, b.iface()); err != nil {
+		return err
+	}
+
+	b.applyFirewall()
+	runHookScript(b.upScript)
+	return writeActiveBackend(backendWireGuard, b.confPath)
+}
+
+func (b *wireguardBackend) Stop() error {
+	if _, err := exec.LookPath("wg-quick"); err == nil {
+		cmd := exec.Command("wg-quick", "down", b.confPath)
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return err
+		}
+	} else if err := exec.Command("ip", "link", "delete", b.iface()).Run(); err != nil {
+		return err
+	}
+
+	runHookScript(b.downScript)
+	if !killswitchPersisted() {
+		killswitchDisable()
+	}
+	clearActiveBackend()
+	return nil
+}
+
+// applyFirewall installs the kill-switch ruleset for the peer's endpoint,
+// the same protection supervisor.applyFirewall gives OpenVPN tunnels. Unlike
+// OpenVPN, the interface name and remote are both known as soon as the
+// interface comes up, so there's no tun-device log line to wait for.
+func (b *wireguardBackend) applyFirewall() {
+	if !killswitchEnabled() {
+		return
+	}
+	conf, err := parseWireGuardConf(b.confPath)
+	if err != nil || len(conf.Peers) == 0 {
+		fmt.Println("Kill-switch not applied, failed to read peer endpoint from config")
+		return
+	}
+	host, port, err := net.SplitHostPort(conf.Peers[0].Endpoint)
+	if err != nil {
+		fmt.Println("Kill-switch not applied, failed to parse peer endpoint:", err)
+		return
+	}
+	if err := killswitchEnable(host, port, b.iface()); err != nil {
+		fmt.Println("Failed to apply kill-switch rules:", err)
+	}
+}
+
+func (b *wireguardBackend) Status() (string, error) {
+	out, err := exec.Command("wg", "show", b.iface()).Output()
+	if err != nil {
+		return "", fmt.Errorf("VPN service is not running")
+	}
+	return "Backend: wireguard\n" + string(out), nil
+}
+
+// Reload re-reads the .conf file. wg-quick has no live-reload of its own,
+// so the simplest correct thing is to bring the interface down and back up.
+func (b *wireguardBackend) Reload() error {
+	if err := b.Stop(); err != nil {
+		return err
+	}
+	return b.Start()
+}
+
+// wireguardConf is the minimal subset of a wg-quick .conf file zvpn needs
+// to program a device directly when wg-quick itself isn't available.
+type wireguardConf struct {
+	PrivateKey string
+	Address    string
+	ListenPort int
+	Peers      []wireguardPeer
+}
+
+type wireguardPeer struct {
+	PublicKey  string
+	Endpoint   string
+	AllowedIPs []string
+}
+
+// parseWireGuardConf hand-rolls the tiny bit of INI syntax wg-quick files
+// use, the same way the rest of zvpn parses .ovpn directives line by line
+// instead of pulling in an INI library for two sections.
+func parseWireGuardConf(path string) (*wireguardConf, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	conf := &wireguardConf{}
+	var peer *wireguardPeer
+	section := ""
+
+	for _, raw := range strings.Split(string(data), "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if section == "Peer" && peer != nil {
+				conf.Peers = append(conf.Peers, *peer)
+			}
+			section = strings.Trim(line, "[]")
+			if section == "Peer" {
+				peer = &wireguardPeer{}
+			}
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+
+		switch section {
+		case "Interface":
+			switch key {
+			case "PrivateKey":
+				conf.PrivateKey = value
+			case "Address":
+				conf.Address = value
+			case "ListenPort":
+				conf.ListenPort, _ = strconv.Atoi(value)
+			}
+		case "Peer":
+			switch key {
+			case "PublicKey":
+				peer.PublicKey = value
+			case "Endpoint":
+				peer.Endpoint = value
+			case "AllowedIPs":
+				for _, ip := range strings.Split(value, ",") {
+					peer.AllowedIPs = append(peer.AllowedIPs, strings.TrimSpace(ip))
+				}
+			}
+		}
+	}
+	if section == "Peer" && peer != nil {
+		conf.Peers = append(conf.Peers, *peer)
+	}
+	return conf, nil
+}
+
+// wireguardStartWithoutQuick recreates what wg-quick does using the kernel
+// WireGuard implementation (via "ip link") and wgctrl to push the config,
+// for hosts that have the kernel module but not the wg-quick script.
+func wireguardStartWithoutQuick(confPath, iface string) error {
+	conf, err := parseWireGuardConf(confPath)
+	if err != nil {
+		return err
+	}
+
+	if err := exec.Command("ip", "link", "add", "dev", iface, "type", "wireguard").Run(); err != nil {
+		return fmt.Errorf("failed to create wireguard interface (is the kernel module loaded?): %w", err)
+	}
+
+	client, err := wgctrl.New()
+	if err != nil {
+		exec.Command("ip", "link", "delete", iface).Run()
+		return err
+	}
+	defer client.Close()
+
+	key, err := wgtypes.ParseKey(conf.PrivateKey)
+	if err != nil {
+		exec.Command("ip", "link", "delete", iface).Run()
+		return err
+	}
+
+	var peers []wgtypes.PeerConfig
+	for _, p := range conf.Peers {
+		pubKey, err := wgtypes.ParseKey(p.PublicKey)
+		if err != nil {
+			exec.Command("ip", "link", "delete", iface).Run()
+			return err
+		}
+		allowedIPs, err := parseAllowedIPs(p.AllowedIPs)
+		if err != nil {
+			exec.Command("ip", "link", "delete", iface).Run()
+			return err
+		}
+		peers = append(peers, wgtypes.PeerConfig{
+			PublicKey:  pubKey,
+			Endpoint:   parseUDPEndpoint(p.Endpoint),
+			AllowedIPs: allowedIPs,
+		})
+	}
+
+	deviceConfig := wgtypes.Config{
+		PrivateKey: &key,
+		Peers:      peers,
+	}
+	if conf.ListenPort != 0 {
+		deviceConfig.ListenPort = &conf.ListenPort
+	}
+
+	if err := client.ConfigureDevice(iface, deviceConfig); err != nil {
+		exec.Command("ip", "link", "delete", iface).Run()
+		return err
+	}
+
+	if conf.Address != "" {
+		exec.Command("ip", "address", "add", conf.Address, "dev", iface).Run()
+	}
+	if err := exec.Command("ip", "link", "set", "up", "dev", iface).Run(); err != nil {
+		return err
+	}
+
+	// wg-quick routes every peer's AllowedIPs through the interface once it's
+	// up; do the same here since nothing else will forward traffic to them.
+	for _, p := range conf.Peers {
+		for _, cidr := range p.AllowedIPs {
+			exec.Command("ip", "route", "add", cidr, "dev", iface).Run()
+		}
+	}
+	return nil
+}
+
+// parseAllowedIPs converts a peer's AllowedIPs CIDR strings into the
+// net.IPNet values wgtypes.PeerConfig needs to scope which destinations are
+// routed to that peer.
+func parseAllowedIPs(cidrs []string) ([]net.IPNet, error) {
+	var allowedIPs []net.IPNet
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid AllowedIPs %q: %w", cidr, err)
+		}
+		allowedIPs = append(allowedIPs, *ipNet)
+	}
+	return allowedIPs, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseWireGuardConf(t *testing.T) {
+	const conf = `[Interface]
+PrivateKey = cHJpdmF0ZWtleQ==
+Address = 10.6.0.2/24
+ListenPort = 51820
+
+[Peer]
+PublicKey = cHVibGlja2V5
+Endpoint = vpn.example.com:51820
+AllowedIPs = 0.0.0.0/0, ::/0
+`
+	path := filepath.Join(t.TempDir(), "wg0.conf")
+	if err := os.WriteFile(path, []byte(conf), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := parseWireGuardConf(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &wireguardConf{
+		PrivateKey: "cHJpdmF0ZWtleQ==",
+		Address:    "10.6.0.2/24",
+		ListenPort: 51820,
+		Peers: []wireguardPeer{
+			{
+				PublicKey:  "cHVibGlja2V5",
+				Endpoint:   "vpn.example.com:51820",
+				AllowedIPs: []string{"0.0.0.0/0", "::/0"},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseWireGuardConf() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseWireGuardConfMultiplePeers(t *testing.T) {
+	const conf = `[Interface]
+PrivateKey = key1
+
+[Peer]
+PublicKey = peerA
+Endpoint = a.example.com:51820
+AllowedIPs = 10.0.0.1/32
+
+[Peer]
+PublicKey = peerB
+Endpoint = b.example.com:51820
+AllowedIPs = 10.0.0.2/32
+`
+	path := filepath.Join(t.TempDir(), "wg1.conf")
+	if err := os.WriteFile(path, []byte(conf), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := parseWireGuardConf(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Peers) != 2 {
+		t.Fatalf("got %d peers, want 2", len(got.Peers))
+	}
+	if got.Peers[0].PublicKey != "peerA" || got.Peers[1].PublicKey != "peerB" {
+		t.Errorf("peers parsed out of order or wrong: %+v", got.Peers)
+	}
+}